@@ -10,20 +10,28 @@
 package main
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"flag"
 	"fmt"
+	"html/template"
 	"io"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	pathpkg "path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"golang.org/x/crypto/acme/autocert"
 	"rsc.io/getopt"
 )
 
@@ -39,58 +47,668 @@ func catch(e error) {
 	}
 }
 
-// The resource's content.
-var content struct {
+// A virtual resource: content cached in memory at path, optionally
+// kept up to date by polling fifo.
+type resource struct {
 	sync.Mutex
 	bytes.Buffer
-	lastMod time.Time
+	lastMod  time.Time
+	revision int
+	updated  *sync.Cond
+
+	path string
+	fifo string
+}
+
+func newResource(path, fifo string) *resource {
+	res := &resource{path: path, fifo: fifo}
+	res.updated = sync.NewCond(&res.Mutex)
+	return res
+}
+
+// Updates the resource's content and wakes up any client waiting
+// on its events stream.
+func (res *resource) cache(r io.Reader) {
+	res.Lock()
+	res.lastMod = time.Now()
+	res.Truncate(0)
+	_, err := res.ReadFrom(r)
+	catch(err)
+	res.revision++
+	res.updated.Broadcast()
+	res.Unlock()
+}
+
+// Polls the resource's FIFO for new content.
+func (res *resource) recacheLoop() {
+	for {
+		f, err := os.Open(res.fifo)
+		catch(err)
+		res.cache(f)
+	}
+}
+
+// Opens res.fifo, which blocks until a writer attaches, checks that
+// it is indeed a FIFO, and caches it onto res. Used for a resource's
+// first open, before recacheLoop takes over.
+func openFIFO(res *resource) {
+	f, err := os.Open(res.fifo)
+	catch(err)
+
+	stat, err := f.Stat()
+	catch(err)
+	if stat.Mode().Type() != fs.ModeNamedPipe {
+		logger.Fatalf("not a FIFO: %s", res.fifo)
+	}
+
+	res.cache(f)
+}
+
+// An upstream poller: fetches url into a resource on a timer,
+// honoring conditional-GET headers to avoid recaching unchanged
+// content.
+type poller struct {
+	client  http.Client
+	url     string
+	headers map[string]string
+	etag    string
+	lastMod string
 }
 
-// Updates the content.
-func cache(r io.Reader) {
-	content.Lock()
-	content.lastMod = time.Now()
-	content.Truncate(0)
-	_, err := content.ReadFrom(r)
+func newPoller(url string, headers map[string]string) *poller {
+	return &poller{client: http.Client{Timeout: 10 * time.Second}, url: url, headers: headers}
+}
+
+// Issues one conditional GET and, on a 200, caches the body on
+// res. Reports request, body-read and status-code errors to logger
+// but never fails fatally on them, since a single bad poll shouldn't
+// kill the server; only a -poll-url that can't be made into a
+// request at all is fatal.
+func (p *poller) poll(res *resource) {
+	req, err := http.NewRequest(http.MethodGet, p.url, nil)
 	catch(err)
-	content.Unlock()
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+	if p.etag != "" {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+	if p.lastMod != "" {
+		req.Header.Set("If-Modified-Since", p.lastMod)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		logger.Print(err)
+		return
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Read the whole body first: cache() treats a read error as
+		// fatal, and a failed poll must leave the old content intact.
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			logger.Printf("polling %s: %v", p.url, err)
+			return
+		}
+		res.cache(bytes.NewReader(body))
+		p.etag = resp.Header.Get("ETag")
+		p.lastMod = resp.Header.Get("Last-Modified")
+	case http.StatusNotModified:
+		// Nothing changed.
+	default:
+		logger.Printf("polling %s: unexpected status %s", p.url, resp.Status)
+	}
+}
+
+// Polls res from p.url every interval, forever.
+func (p *poller) loop(res *resource, interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		p.poll(res)
+	}
 }
 
 var (
-	path      string // The reource's (virtual) path.
-	directory string // The directory to serve from.
-	fifo      string // The FIFO to poll.
-	address   string // Address and port to bind to.
+	path      string      // The primary resource's (virtual) path.
+	fifo      string      // The FIFO to poll for the primary resource.
+	resources []*resource // All registered resources, primary first.
+
+	directory   string // The directory (or archive) to serve from.
+	directoryFS fs.FS  // directory opened as an fs.FS, or nil.
+	address     string // Address and port to bind to.
+
+	tlsCert     string // Certificate file for TLS.
+	tlsKey      string // Key file for TLS.
+	autocertFor string // Comma-separated hosts to autocert.
+	cacheDir    string // Cache directory for autocert.
+	redirectTo  string // Address to redirect plain HTTP from.
+
+	eventsPath    string // Path serving the update event stream.
+	eventsEnabled bool   // Whether the event stream was requested.
+	liveReload    bool   // Inject the live-reload script into served HTML.
+
+	extraResources resourceFlag // Additional -r <path>=<fifo> resources.
+
+	pollURL      string        // Upstream URL to poll instead of a FIFO.
+	pollInterval time.Duration // Interval between polls.
+	pollHeaders  headerFlag    // Extra headers to send while polling.
+
+	browse         bool   // Render a templated index for bare directories.
+	hidden         bool   // Include dotfiles in the index.
+	browseTemplate string // File overriding the default index template.
 )
 
-// Serves the resource. If directory is nonempty, serves
-// anything else from there.
+// A repeatable -poll-header <key>=<value> flag.
+type headerFlag map[string]string
+
+func (f *headerFlag) String() string {
+	return fmt.Sprint(map[string]string(*f))
+}
+
+func (f *headerFlag) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("malformed -poll-header value %q, want <key>=<value>", s)
+	}
+	if *f == nil {
+		*f = headerFlag{}
+	}
+	(*f)[k] = v
+	return nil
+}
+
+// A repeatable -r <path>=<fifo> flag that accumulates one
+// (path, fifo) pair per occurrence.
+type resourceFlag []*resource
+
+func (f *resourceFlag) String() string {
+	return fmt.Sprint([]*resource(*f))
+}
+
+func (f *resourceFlag) Set(s string) error {
+	p, fifo, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("malformed -r value %q, want <path>=<fifo>", s)
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	*f = append(*f, newResource(p, fifo))
+	return nil
+}
+
+// Serves the most specific resource registered under the request
+// path (see matchResource). If directory is nonempty, falls through
+// to serving anything else from there.
 func serve(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path == path {
-		content.Lock()
-		http.ServeContent(w, r, filepath.Base(path), content.lastMod,
-			bytes.NewReader(content.Bytes()))
-		content.Unlock()
-	} else if directory == "" {
+	if res := matchResource(r.URL.Path); res != nil {
+		res.Lock()
+		b := res.Bytes()
+		if res == primary() && liveReload && looksLikeHTML(b) {
+			b = injectLiveReloadScript(b)
+		}
+		http.ServeContent(w, r, filepath.Base(res.path), res.lastMod,
+			bytes.NewReader(b))
+		res.Unlock()
+	} else if directoryFS == nil {
 		http.NotFound(w, r)
+	} else if name := browsableDir(r.URL.Path); browse && name != "" {
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			// Send relative links in the rendered index (and any
+			// future request for them) to the right place.
+			u := *r.URL
+			u.Path += "/"
+			http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+			return
+		}
+		renderBrowseIndex(w, r, name)
 	} else {
-		http.ServeFile(w, r, filepath.Join(directory, r.URL.Path))
+		http.ServeFileFS(w, r, directoryFS, r.URL.Path)
+	}
+}
+
+// If reqPath names a directory in directoryFS that has no
+// index.html, returns its fs.FS-relative name; otherwise "".
+func browsableDir(reqPath string) string {
+	name := strings.TrimPrefix(pathpkg.Clean(reqPath), "/")
+	if name == "" {
+		name = "."
+	}
+	if info, err := fs.Stat(directoryFS, name); err != nil || !info.IsDir() {
+		return ""
+	}
+	if _, err := fs.Stat(directoryFS, pathpkg.Join(name, "index.html")); err == nil {
+		return ""
+	}
+	return name
+}
+
+// Returns the most specific registered resource whose path is a
+// "/"-boundary prefix of reqPath, or nil if none is. A resource at
+// "/api/users" thus wins over one at "/api" for a request to
+// "/api/users/42", and ties (equal-length paths) can't happen since
+// parseArgs rejects duplicate resource paths.
+//
+// The root path "/" is the one deliberate exception: it matches only
+// the exact request "/", not every path. -d's canonical invocation
+// (abserve -d <directory> /) registers the primary resource at "/"
+// precisely so everything else falls through to the served
+// directory; treating "/" as a prefix of every path would make -d
+// permanently unreachable whenever a resource occupies the root.
+func matchResource(reqPath string) *resource {
+	var best *resource
+	for _, res := range resources {
+		if !resourceMatches(res.path, reqPath) {
+			continue
+		}
+		if best == nil || len(res.path) > len(best.path) {
+			best = res
+		}
 	}
+	return best
+}
+
+// Reports whether resPath is a registered path that should serve
+// reqPath, per the rules documented on matchResource.
+func resourceMatches(resPath, reqPath string) bool {
+	if resPath == "/" {
+		return reqPath == "/"
+	}
+	return reqPath == resPath || strings.HasPrefix(reqPath, resPath+"/")
+}
+
+// The first registered resource, i.e. the one described by the
+// positional <path> argument and -p.
+func primary() *resource {
+	return resources[0]
 }
 
-// Polls the FIFO for new content.
-func recacheLoop() {
+// Opens the filesystem named by -d, dispatching on its extension:
+// a .zip is read through archive/zip, a .tar or .tar.gz/.tgz is
+// read fully into memory, and anything else is served straight
+// off the OS as a plain directory.
+func openDirFS(name string) (fs.FS, error) {
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return zip.OpenReader(name)
+	case strings.HasSuffix(name, ".tar"):
+		return openTarFS(name, false)
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return openTarFS(name, true)
+	default:
+		return os.DirFS(name), nil
+	}
+}
+
+// Reads the named tar archive (gzip-compressed if gz) fully into
+// memory and returns it as an fs.FS.
+func openTarFS(name string, gz bool) (fs.FS, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if gz {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	files := map[string]*tarFile{}
+	tr := tar.NewReader(r)
 	for {
-		f, err := os.Open(fifo)
-		catch(err)
-		cache(f)
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data := make([]byte, hdr.Size)
+		if _, err := io.ReadFull(tr, data); err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(hdr.Name, "/")
+		files[name] = &tarFile{data: data, mode: fs.FileMode(hdr.Mode), modTime: hdr.ModTime}
+	}
+	return newTarFS(files), nil
+}
+
+// A regular file extracted from a tar archive, read fully into
+// memory.
+type tarFile struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (f *tarFile) info(name string) fs.FileInfo {
+	return tarFileInfo{name: pathpkg.Base(name), size: int64(len(f.data)), mode: f.mode, modTime: f.modTime}
+}
+
+// A minimal fs.FileInfo, shared by regular tarFiles and the
+// directories tarFS synthesizes for them.
+type tarFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i tarFileInfo) Name() string       { return i.name }
+func (i tarFileInfo) Size() int64        { return i.size }
+func (i tarFileInfo) Mode() fs.FileMode  { return i.mode }
+func (i tarFileInfo) ModTime() time.Time { return i.modTime }
+func (i tarFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i tarFileInfo) Sys() any           { return nil }
+
+// A read-only, in-memory fs.FS over a tar archive's regular files.
+// Directories aren't stored explicitly; they're derived once, at
+// construction, from the files' paths.
+type tarFS struct {
+	files map[string]*tarFile // path (no leading "/") -> file
+	dirs  map[string][]string // dir path ("." for root) -> sorted immediate children
+}
+
+func newTarFS(files map[string]*tarFile) *tarFS {
+	dirs := map[string][]string{}
+	add := func(dir, child string) {
+		for _, name := range dirs[dir] {
+			if name == child {
+				return
+			}
+		}
+		dirs[dir] = append(dirs[dir], child)
+	}
+	for name := range files {
+		dir := pathpkg.Dir(name)
+		add(dir, pathpkg.Base(name))
+		for dir != "." {
+			parent := pathpkg.Dir(dir)
+			add(parent, pathpkg.Base(dir))
+			dir = parent
+		}
+	}
+	for _, children := range dirs {
+		sort.Strings(children)
+	}
+	return &tarFS{files: files, dirs: dirs}
+}
+
+func (t *tarFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if f, ok := t.files[name]; ok {
+		return &openTarFile{info: f.info(name), r: bytes.NewReader(f.data)}, nil
+	}
+	if children, ok := t.dirs[name]; ok {
+		info := tarFileInfo{name: pathpkg.Base(name), mode: fs.ModeDir}
+		return &openTarDir{info: info, fs: t, dir: name, children: children}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// An open regular file from a tarFS.
+type openTarFile struct {
+	info fs.FileInfo
+	r    *bytes.Reader
+}
+
+func (f *openTarFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *openTarFile) Read(b []byte) (int, error) { return f.r.Read(b) }
+func (f *openTarFile) Close() error               { return nil }
+
+func (f *openTarFile) Seek(offset int64, whence int) (int64, error) {
+	return f.r.Seek(offset, whence)
+}
+
+// An open, synthesized directory from a tarFS.
+type openTarDir struct {
+	info     fs.FileInfo
+	fs       *tarFS
+	dir      string
+	children []string
+	pos      int
+}
+
+func (d *openTarDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *openTarDir) Close() error               { return nil }
+
+func (d *openTarDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.dir, Err: fs.ErrInvalid}
+}
+
+func (d *openTarDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		n = len(d.children) - d.pos
+	} else if d.pos >= len(d.children) {
+		return nil, io.EOF
+	}
+	entries := make([]fs.DirEntry, 0, n)
+	for ; d.pos < len(d.children) && len(entries) < n; d.pos++ {
+		entries = append(entries, d.entry(d.children[d.pos]))
+	}
+	return entries, nil
+}
+
+func (d *openTarDir) entry(name string) fs.DirEntry {
+	path := name
+	if d.dir != "." {
+		path = d.dir + "/" + name
+	}
+	if f, ok := d.fs.files[path]; ok {
+		return fs.FileInfoToDirEntry(f.info(path))
+	}
+	return fs.FileInfoToDirEntry(tarFileInfo{name: name, mode: fs.ModeDir})
+}
+
+// The parsed -browse index template, set up in main.
+var browseIndexTemplate *template.Template
+
+// The default index template, overridable with -browse-template.
+const defaultBrowseTemplate = `<!DOCTYPE html>
+<html>
+<head><title>Index of {{.Path}}</title></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+<tr><th><a href="?sort=name">Name</a></th><th><a href="?sort=size">Size</a></th><th><a href="?sort=mtime">Modified</a></th></tr>
+{{if .Parent}}<tr><td><a href="{{.Parent}}">../</a></td><td></td><td></td></tr>{{end}}
+{{range .Entries}}<tr><td><a href="{{.Name}}{{if .IsDir}}/{{end}}">{{.Name}}{{if .IsDir}}/{{end}}</a></td><td>{{.Size}}</td><td>{{.ModTime}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`
+
+// One row of a rendered directory index.
+type browseEntry struct {
+	Name    string
+	IsDir   bool
+	Size    string
+	ModTime string
+	size    int64
+	modTime time.Time
+}
+
+type browseIndex struct {
+	Path    string
+	Parent  string
+	Entries []browseEntry
+}
+
+// Formats n bytes as a human-readable size in KB/MB/GB.
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Renders a directory index for name (an fs.FS path, "." for the
+// root) using browseIndexTemplate, honoring -hidden and the
+// ?sort= query parameter ("name", "size" or "mtime").
+func renderBrowseIndex(w http.ResponseWriter, r *http.Request, name string) {
+	entries, err := fs.ReadDir(directoryFS, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	idx := browseIndex{Path: r.URL.Path}
+	if name != "." {
+		idx.Parent = ".."
+	}
+
+	for _, e := range entries {
+		if !hidden && strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		size := info.Size()
+		row := browseEntry{
+			Name: e.Name(), IsDir: e.IsDir(),
+			size: size, modTime: info.ModTime(),
+			ModTime: info.ModTime().Format("2006-01-02 15:04"),
+		}
+		if e.IsDir() {
+			row.Size = ""
+		} else {
+			row.Size = humanSize(size)
+		}
+		idx.Entries = append(idx.Entries, row)
+	}
+
+	sortEntries(idx.Entries, r.URL.Query().Get("sort"))
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	catch(browseIndexTemplate.Execute(w, idx))
+}
+
+// Sorts entries in place, directories first, by the given key
+// ("name", "size" or "mtime"; "name" is the default).
+func sortEntries(entries []browseEntry, key string) {
+	less := func(i, j int) bool { return entries[i].Name < entries[j].Name }
+	switch key {
+	case "size":
+		less = func(i, j int) bool { return entries[i].size < entries[j].size }
+	case "mtime":
+		less = func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) }
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return less(i, j)
+	})
+}
+
+// Reports whether b sniffs as HTML.
+func looksLikeHTML(b []byte) bool {
+	return strings.HasPrefix(http.DetectContentType(b), "text/html")
+}
+
+// The script injected into served HTML when -livereload is set.
+const liveReloadScript = `<script>
+new EventSource(%q).addEventListener("update", () => location.reload());
+</script>
+`
+
+// Appends the live-reload script to b, right before </body> if
+// present, or at the end otherwise.
+func injectLiveReloadScript(b []byte) []byte {
+	script := []byte(fmt.Sprintf(liveReloadScript, eventsPath))
+	if i := bytes.LastIndex(b, []byte("</body>")); i >= 0 {
+		out := make([]byte, 0, len(b)+len(script))
+		out = append(out, b[:i]...)
+		out = append(out, script...)
+		out = append(out, b[i:]...)
+		return out
+	}
+	return append(b, script...)
+}
+
+// Serves a text/event-stream that pushes the primary resource's
+// latest revision number whenever cache() has written new content
+// since the last push; updates that land while a push is being
+// written are coalesced into the next one.
+func events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ctx := r.Context()
+	res := primary()
+
+	// sync.Cond has no way to wait on ctx.Done(), so nudge the
+	// waiter below with a spurious broadcast once the client goes
+	// away; otherwise a disconnected client with no further updates
+	// would hold its goroutine (and this handler) open forever.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			res.Lock()
+			res.updated.Broadcast()
+			res.Unlock()
+		case <-done:
+		}
+	}()
+
+	res.Lock()
+	last := res.revision
+	for {
+		for res.revision == last && ctx.Err() == nil {
+			res.updated.Wait()
+		}
+		if ctx.Err() != nil {
+			res.Unlock()
+			return
+		}
+		last = res.revision
+		res.Unlock()
+
+		// Write to the client with the lock released, so a slow
+		// or stalled reader can't block cache() or plain GETs of
+		// this resource.
+		fmt.Fprintf(w, "event: update\ndata: %d\n\n", last)
+		flusher.Flush()
+
+		res.Lock()
 	}
 }
 
 func synopsis() {
 	fmt.Fprintf(
 		os.Stderr,
-		"Usage: %s [-h] [-p <fifo>] [-d <directory>] [--] [<path>]\n",
+		"Usage: %s [-h] [-p <fifo>] [-r <path>=<fifo>]... [-d <directory>] [--] [<path>]\n",
 		prog)
 }
 
@@ -100,13 +718,33 @@ func parseArgs() {
 	var printVersion bool
 
 	flag.StringVar(&directory, "d", "",
-		"serve everything else from `<directory>`")
+		"serve everything else from `<directory>` (or a .zip/.tar/.tar.gz archive)")
 	flag.StringVar(&fifo, "p", "",
 		"ignore input and cache `<fifo>` (which must be a FIFO) on loop instead")
 	flag.StringVar(&address, "l", ":8080", "listen on `<address>:<port>`")
 	flag.BoolVar(&help, "h", false, "print this")
 	flag.BoolVar(&printVersion, "version", false, "print version")
 
+	flag.StringVar(&tlsCert, "tls-cert", "", "serve over TLS using `<file>` as the certificate")
+	flag.StringVar(&tlsKey, "tls-key", "", "serve over TLS using `<file>` as the key")
+	flag.StringVar(&autocertFor, "autocert", "", "serve over TLS, provisioning certificates for `<host,host,...>` via Let's Encrypt")
+	flag.StringVar(&cacheDir, "cache-dir", "", "cache autocert certificates in `<directory>`")
+	flag.StringVar(&redirectTo, "redirect-http", "", "redirect plain HTTP from `<address>:<port>` to the TLS listener")
+
+	flag.StringVar(&eventsPath, "events", "", "serve a live-reload event stream at `<path>`")
+	flag.BoolVar(&liveReload, "livereload", false, "inject a live-reload script into served HTML")
+
+	flag.Var(&extraResources, "r",
+		"additionally serve `<path>=<fifo>`, polling fifo on its own loop (repeatable)")
+
+	flag.StringVar(&pollURL, "poll-url", "", "cache the primary resource from `<url>` instead of stdin/-p")
+	flag.DurationVar(&pollInterval, "poll-interval", 30*time.Second, "poll -poll-url every `<duration>`")
+	flag.Var(&pollHeaders, "poll-header", "send `<key>=<value>` on every -poll-url request (repeatable)")
+
+	flag.BoolVar(&browse, "browse", false, "render a templated index for -d directories without an index.html")
+	flag.BoolVar(&hidden, "hidden", false, "include dotfiles in the -browse index")
+	flag.StringVar(&browseTemplate, "browse-template", "", "render the -browse index using `<file>` instead of the built-in template")
+
 	getopt.Alias("d", "directory")
 	getopt.Alias("p", "poll")
 	getopt.Alias("l", "listen")
@@ -142,6 +780,30 @@ Options:
 	if path = flag.Arg(0); !strings.HasPrefix(path, "/") {
 		path = "/" + path
 	}
+
+	eventsEnabled = eventsPath != "" || liveReload
+	if eventsPath == "" {
+		eventsPath = path + ".events"
+	}
+
+	resources = append([]*resource{newResource(path, fifo)}, extraResources...)
+	seen := make(map[string]bool, len(resources))
+	for _, res := range resources {
+		if seen[res.path] {
+			logger.Fatalf("duplicate resource path: %s", res.path)
+		}
+		seen[res.path] = true
+	}
+
+	if (tlsCert != "") != (tlsKey != "") {
+		logger.Fatal("-tls-cert and -tls-key must be given together")
+	}
+	if autocertFor != "" && tlsCert != "" {
+		logger.Fatal("-autocert and -tls-cert/-tls-key are mutually exclusive")
+	}
+	if pollURL != "" && fifo != "" {
+		logger.Fatal("-poll-url and -p are mutually exclusive")
+	}
 }
 
 func main() {
@@ -155,23 +817,100 @@ func main() {
 
 	parseArgs()
 
-	if fifo != "" {
-		f, err := os.Open(fifo)
+	if directory != "" {
+		fsys, err := openDirFS(directory)
 		catch(err)
+		directoryFS = fsys
+	}
 
-		// Check that the file is indeed a FIFO.
-		stat, err := f.Stat()
-		catch(err)
-		if stat.Mode().Type() != fs.ModeNamedPipe {
-			logger.Fatalf("not a FIFO: %s", fifo)
+	if browse {
+		text := defaultBrowseTemplate
+		if browseTemplate != "" {
+			b, err := os.ReadFile(browseTemplate)
+			catch(err)
+			text = string(b)
 		}
+		t, err := template.New("browse").Parse(text)
+		catch(err)
+		browseIndexTemplate = t
+	}
 
-		cache(f)
-		go recacheLoop()
-	} else {
-		cache(os.Stdin)
+	switch {
+	case pollURL != "":
+		p := newPoller(pollURL, pollHeaders)
+		p.poll(primary())
+		go p.loop(primary(), pollInterval)
+	case primary().fifo != "":
+		openFIFO(primary())
+		go primary().recacheLoop()
+	default:
+		primary().cache(os.Stdin)
+	}
+	for _, res := range resources[1:] {
+		if res.fifo == "" {
+			continue
+		}
+		// Opening a FIFO blocks until a writer attaches, so a
+		// producer that hasn't started yet must not hold up the
+		// primary resource and -d content from being served; each
+		// extra resource gets its own goroutine for this.
+		res := res
+		go func() {
+			openFIFO(res)
+			res.recacheLoop()
+		}()
 	}
 
 	http.HandleFunc("/", serve)
-	logger.Fatal(http.ListenAndServe(address, nil))
+	if eventsEnabled {
+		http.HandleFunc(eventsPath, events)
+	}
+
+	switch {
+	case autocertFor != "":
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(strings.Split(autocertFor, ",")...),
+		}
+		if cacheDir != "" {
+			manager.Cache = autocert.DirCache(cacheDir)
+		}
+		if redirectTo != "" {
+			go redirectHTTP(manager.HTTPHandler(nil))
+		}
+		server := &http.Server{Addr: address, TLSConfig: manager.TLSConfig()}
+		logger.Fatal(server.ListenAndServeTLS("", ""))
+	case tlsCert != "":
+		if redirectTo != "" {
+			go redirectHTTP(nil)
+		}
+		logger.Fatal(http.ListenAndServeTLS(address, tlsCert, tlsKey, nil))
+	default:
+		logger.Fatal(http.ListenAndServe(address, nil))
+	}
+}
+
+// Runs a companion listener that redirects plain HTTP to the
+// TLS address, or serves h (the ACME HTTP-01 challenge handler)
+// when h is non-nil.
+func redirectHTTP(h http.Handler) {
+	// Redirect to the port abserve itself is listening on for TLS,
+	// not whatever port the client's Host header happened to name;
+	// the two differ behind a NAT or port-mapping reverse proxy.
+	_, tlsPort, err := net.SplitHostPort(address)
+	catch(err)
+
+	if h == nil {
+		h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := r.Host
+			if hostname, _, err := net.SplitHostPort(host); err == nil {
+				host = hostname
+			}
+			u := *r.URL
+			u.Scheme = "https"
+			u.Host = net.JoinHostPort(host, tlsPort)
+			http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+		})
+	}
+	catch(http.ListenAndServe(redirectTo, h))
 }
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHumanSize(t *testing.T) {
+	cases := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{1 << 20, "1.0 MB"},
+		{1 << 30, "1.0 GB"},
+	}
+	for _, c := range cases {
+		if got := humanSize(c.n); got != c.want {
+			t.Errorf("humanSize(%d) = %q, want %q", c.n, got, c.want)
+		}
+	}
+}
+
+func TestSortEntries(t *testing.T) {
+	entries := []browseEntry{
+		{Name: "b.txt", size: 20, modTime: time.Unix(200, 0)},
+		{Name: "sub", IsDir: true, modTime: time.Unix(300, 0)},
+		{Name: "a.txt", size: 10, modTime: time.Unix(100, 0)},
+	}
+
+	sortEntries(entries, "name")
+	wantNames := []string{"sub", "a.txt", "b.txt"}
+	for i, want := range wantNames {
+		if entries[i].Name != want {
+			t.Fatalf("sort by name: entries[%d] = %q, want %q", i, entries[i].Name, want)
+		}
+	}
+
+	sortEntries(entries, "size")
+	if entries[0].Name != "sub" || entries[1].Name != "a.txt" || entries[2].Name != "b.txt" {
+		t.Fatalf("sort by size: dirs should stay first, got %+v", entries)
+	}
+
+	sortEntries(entries, "mtime")
+	if entries[1].Name != "a.txt" || entries[2].Name != "b.txt" {
+		t.Fatalf("sort by mtime: got %+v", entries)
+	}
+}
+
+func TestBrowsableDir(t *testing.T) {
+	files := map[string]*tarFile{
+		"index.html":  {data: []byte("<html></html>")},
+		"assets/a.js": {data: []byte("// js")},
+	}
+	directoryFS = newTarFS(files)
+
+	if name := browsableDir("/assets"); name != "assets" {
+		t.Errorf("browsableDir(/assets) = %q, want %q", name, "assets")
+	}
+	if name := browsableDir("/"); name != "" {
+		t.Errorf("browsableDir(/) = %q, want \"\" (index.html present)", name)
+	}
+	if name := browsableDir("/assets/a.js"); name != "" {
+		t.Errorf("browsableDir(/assets/a.js) = %q, want \"\" (not a directory)", name)
+	}
+}
+
+func TestTarFS(t *testing.T) {
+	files := map[string]*tarFile{
+		"top.txt":        {data: []byte("hello")},
+		"sub/nested.txt": {data: []byte("world")},
+		"sub/deep/x.txt": {data: []byte("x")},
+	}
+	tfs := newTarFS(files)
+
+	b, err := fs.ReadFile(tfs, "sub/nested.txt")
+	if err != nil || string(b) != "world" {
+		t.Fatalf("ReadFile(sub/nested.txt) = %q, %v, want %q, nil", b, err, "world")
+	}
+
+	entries, err := fs.ReadDir(tfs, "sub")
+	if err != nil {
+		t.Fatalf("ReadDir(sub): %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != 2 || names[0] != "deep" || names[1] != "nested.txt" {
+		t.Fatalf("ReadDir(sub) = %v, want [deep nested.txt]", names)
+	}
+
+	if _, err := fs.Stat(tfs, "sub/deep"); err != nil {
+		t.Fatalf("Stat(sub/deep): %v", err)
+	}
+	if _, err := fs.Stat(tfs, "nope"); err == nil {
+		t.Fatal("Stat(nope) succeeded, want error")
+	}
+}
+
+func TestMatchResource(t *testing.T) {
+	saved := resources
+	defer func() { resources = saved }()
+
+	resources = []*resource{
+		newResource("/", ""),
+		newResource("/dash", ""),
+		newResource("/dash/cpu", ""),
+	}
+
+	cases := []struct {
+		reqPath string
+		want    string // wanted resource path, or "" for no match
+	}{
+		{"/", "/"},
+		{"/other", ""},
+		{"/dash", "/dash"},
+		{"/dash/cpu", "/dash/cpu"},
+		{"/dash/cpu/live", "/dash/cpu"},
+		{"/dash/mem", "/dash"},
+	}
+	for _, c := range cases {
+		res := matchResource(c.reqPath)
+		var got string
+		if res != nil {
+			got = res.path
+		}
+		if got != c.want {
+			t.Errorf("matchResource(%q) = %q, want %q", c.reqPath, got, c.want)
+		}
+	}
+}
+
+func TestResourceCacheBroadcastsUpdate(t *testing.T) {
+	res := newResource("/", "")
+
+	woke := make(chan int, 1)
+	go func() {
+		res.Lock()
+		last := res.revision
+		for res.revision == last {
+			res.updated.Wait()
+		}
+		woke <- res.revision
+		res.Unlock()
+	}()
+
+	time.Sleep(10 * time.Millisecond) // give the goroutine a chance to start waiting
+	res.cache(strings.NewReader("hello"))
+
+	select {
+	case rev := <-woke:
+		if rev != 1 {
+			t.Errorf("woke with revision %d, want 1", rev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cache() did not wake a waiter within 1s")
+	}
+}